@@ -12,14 +12,19 @@ import (
 	"github.com/spacemeshos/post/initialization"
 	"github.com/spacemeshos/post/proving"
 	"github.com/spacemeshos/post/shared"
+	"github.com/spacemeshos/post/shared/cpufeat"
 	"github.com/spacemeshos/post/validation"
 	"io"
 	"log"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"runtime"
 	"runtime/pprof"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -37,15 +42,19 @@ const (
 	single benchMode = 1 + iota
 	mid
 	full
+	cgroupMode
+	concurrentMode
 )
 
 var modes = []string{
 	"single",
 	"mid",
 	"full",
+	"cgroup",
+	"concurrent",
 }
 
-func (m benchMode) isValid() bool { return m >= single && m <= full }
+func (m benchMode) isValid() bool { return m >= single && m <= concurrentMode }
 
 func (m benchMode) String() string { return modes[m-1] }
 
@@ -56,14 +65,22 @@ func main() {
 	flag.UintVar(&defConfig.MaxWriteFilesParallelism, "pfiles", defConfig.MaxWriteFilesParallelism, "max degree of files write parallelism (in single mode only, otherwise it is autogenerated)")
 	flag.UintVar(&defConfig.MaxWriteInFileParallelism, "pinfile", defConfig.MaxWriteInFileParallelism, "max degree of cpu work parallelism per file write (in single mode only, otherwise it is autogenerated)")
 	flag.UintVar(&defConfig.MaxReadFilesParallelism, "pread", defConfig.MaxReadFilesParallelism, "max degree of files read parallelism (in single mode only, otherwise it is autogenerated)")
-	mode := flag.Int("mode", int(mid), fmt.Sprintf("benchmark mode: %v=%d,%v=%d, %v=%d",
-		single, single, mid, mid, full, full))
+	mode := flag.Int("mode", int(mid), fmt.Sprintf("benchmark mode: %v=%d,%v=%d, %v=%d, %v=%d, %v=%d",
+		single, single, mid, mid, full, full, cgroupMode, cgroupMode, concurrentMode, concurrentMode))
 	disktype := flag.String("disktype", "", "specify the disk type (to be used in report)")
 	fstype := flag.String("fstype", "", "specify the file-system type (to be used in report)")
 	desc := flag.String("desc", "", "specify the test run description (to be used in report)")
 	cpuprof := flag.String("cpuprof", "", "write cpu profile to file")
 	memprof := flag.String("memprof", "", "write memory profile to file")
 	report := flag.String("report", "report.csv", "write report csv to file")
+	cpuQuota := flag.String("cpu-quota", "", "cgroup mode only: comma-separated cpu.max quotas, e.g. 200%,400%,800%")
+	memMax := flag.String("mem-max", "", "cgroup mode only: comma-separated memory.max caps, e.g. 2GiB,4GiB")
+	ioMax := flag.String("io-max", "", "cgroup mode only: io.max cap as /dev/<name>:<rate>, e.g. /dev/nvme0n1:200MiB")
+	iodev := flag.String("iodev", "", "comma-separated block device names to restrict disk io counters to, e.g. nvme0n1 (defaults to all devices)")
+	features := flag.String("features", "", fmt.Sprintf(
+		"comma-separated CPU feature subsets to force off one at a time, e.g. avx2,sse41,none (combine with '+', e.g. avx2+sse41; recognised: %s)",
+		strings.Join(cpufeat.Names(), ",")))
+	concurrency := flag.String("concurrency", "1", "concurrent mode only: comma-separated worker counts to benchmark, e.g. 1,2,4,8")
 
 	flag.Parse()
 
@@ -87,84 +104,401 @@ func main() {
 	log.Printf("bench config: mode: %v, datadir: %v, space: %v",
 		benchMode, defConfig.DataDir, bytefmt.ByteSize(defConfig.SpacePerUnit))
 
-	cases := genTestCases(benchMode)
-	data := make([][]string, 0)
-	for i, cfg := range cases {
-		log.Printf("test %v/%v starting...", i+1, len(cases))
-		tStart := time.Now()
+	iodevs := parseCSVFlag(*iodev)
+	var header []string
+	var data [][]string
+
+	if benchMode == concurrentMode {
+		header = []string{"CONCURRENCY", "AGG-THROUGHPUT", "INIT-MEAN", "INIT-P50", "INIT-P95", "EXEC-MEAN", "EXEC-P50", "EXEC-P95", "WALL"}
+		data = runConcurrentCases(*defConfig, parseCSVFlag(*concurrency))
+	} else {
+		// Init/proving don't expose a commit-level progress hook (tracked as a
+		// follow-up), so the progress bar below approximates "done" from sampled disk
+		// I/O deltas rather than actual file/label batches committed.
+		log.Printf("note: progress is approximated from sampled disk I/O, not direct init/proving batch commits")
+
+		caseMode := benchMode
+		if caseMode == cgroupMode {
+			// cgroup mode benchmarks the caps themselves; reuse the "mid" case set as the
+			// base Config matrix that each limit combination is run against.
+			caseMode = mid
+		}
+		cases := genTestCases(caseMode)
+		header = []string{"NUMFILES", "P-FILES", "P-INFILE", "INIT", "INIT-V", "P-READ", "EXEC", "EXEC-V"}
+		for _, phase := range []string{"INIT", "INITV", "EXEC", "EXECV"} {
+			header = append(header,
+				phase+"-RB", phase+"-WB", phase+"-ROPS", phase+"-WOPS", phase+"-RSS")
+		}
+
+		switch {
+		case benchMode == cgroupMode:
+			if *features != "" {
+				log.Printf("warning: -features is ignored in cgroup mode")
+			}
+			header = append([]string{"CPU-QUOTA", "MEM-MAX", "IO-MAX"}, header...)
+			data = runCgroupCases(cases, parseCSVFlag(*cpuQuota), parseCSVFlag(*memMax), *ioMax, iodevs)
+		case *features != "":
+			header = append([]string{"FEATURES"}, header...)
+			data = runFeatureCases(cases, parseCSVFlag(*features), iodevs)
+		default:
+			for i, cfg := range cases {
+				log.Printf("test %v/%v starting...", i+1, len(cases))
+				data = append(data, runCase(cfg, i, len(cases), iodevs))
+			}
+		}
+	}
 
-		init := initialization.NewInitializer(&cfg, shared.DisabledLogger{})
-		prover := proving.NewProver(&cfg, shared.DisabledLogger{})
-		validator := validation.NewValidator(&cfg)
+	metadata := getMetadata(defConfig, *disktype, *fstype, *desc)
+
+	exportTable(metadata, header, data, os.Stdout)
+	exportCSV(metadata, header, data, *report)
 
-		t := time.Now()
-		proof, err := init.Initialize(id)
+	if *memprof != "" {
+		f, err := os.Create(*memprof)
 		if err != nil {
-			log.Fatal(err)
+			log.Fatal("could not create memory profile: ", err)
 		}
-		eInit := time.Since(t)
+		defer f.Close()
+		runtime.GC() // Get up-to-date statistics.
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			log.Fatal("could not write memory profile: ", err)
+		}
+	}
+}
+
+// runCase runs a single init/validate/prove/validate/reset cycle for cfg and returns
+// the resulting report row, including per-phase disk I/O and peak RSS sampled over
+// iodevs (all devices, if empty). i and total are used for progress logging only.
+func runCase(cfg Config, i, total int, iodevs []string) []string {
+	tStart := time.Now()
+
+	init := initialization.NewInitializer(&cfg, shared.DisabledLogger{})
+	prover := proving.NewProver(&cfg, shared.DisabledLogger{})
+	validator := validation.NewValidator(&cfg)
+
+	initProgress := newProgressReporter(i, total, "init")
+	initPoller := startProgressPoller(initProgress, cfg.SpacePerUnit, iodevs, func(s ioSample) uint64 { return s.writeBytes })
+
+	ioStart, rss := beginPhase(iodevs)
+	t := time.Now()
+	proof, err := init.Initialize(id)
+	if err != nil {
+		log.Fatal(err)
+	}
+	eInit, ioInit, rssInit := endPhase(t, ioStart, rss, iodevs)
+	initPoller.stop()
+	initProgress.finish()
+
+	ioStart, rss = beginPhase(iodevs)
+	t = time.Now()
+	err = validator.Validate(proof)
+	if err != nil {
+		log.Fatal(err)
+	}
+	eInitV, ioInitV, rssInitV := endPhase(t, ioStart, rss, iodevs)
+
+	execProgress := newProgressReporter(i, total, "exec")
+	execPoller := startProgressPoller(execProgress, cfg.SpacePerUnit, iodevs, func(s ioSample) uint64 { return s.readBytes })
 
-		t = time.Now()
-		err = validator.Validate(proof)
+	ioStart, rss = beginPhase(iodevs)
+	t = time.Now()
+	proof, err = prover.GenerateProof(id, challenge)
+	if err != nil {
+		log.Fatal(err)
+	}
+	eExec, ioExec, rssExec := endPhase(t, ioStart, rss, iodevs)
+	execPoller.stop()
+	execProgress.finish()
+
+	ioStart, rss = beginPhase(iodevs)
+	t = time.Now()
+	err = validator.Validate(proof)
+	if err != nil {
+		log.Fatal(err)
+	}
+	eExecV, ioExecV, rssExecV := endPhase(t, ioStart, rss, iodevs)
+
+	err = init.Reset(id)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("test %v/%v completed, %v", i+1, total, time.Since(tStart))
+
+	numFiles, _ := shared.NumFiles(cfg.SpacePerUnit, cfg.FileSize)
+	pfiles, pinfile := init.CalcParallelism()
+	pread := prover.CalcParallelism(numFiles)
+
+	row := []string{
+		strconv.Itoa(numFiles),
+		strconv.Itoa(pfiles),
+		strconv.Itoa(pinfile),
+		eInit.Round(time.Duration(time.Millisecond)).String(),
+		eInitV.Round(time.Duration(time.Microsecond)).String(),
+		strconv.Itoa(pread),
+		eExec.Round(time.Duration(time.Millisecond)).String(),
+		eExecV.Round(time.Duration(time.Microsecond)).String(),
+	}
+	for _, p := range []struct {
+		io  ioSample
+		rss uint64
+	}{
+		{ioInit, rssInit},
+		{ioInitV, rssInitV},
+		{ioExec, rssExec},
+		{ioExecV, rssExecV},
+	} {
+		row = append(row, p.io.row()...)
+		row = append(row, strconv.FormatUint(p.rss, 10))
+	}
+	return row
+}
+
+// runCgroupCases runs every case in cases once per combination of cpuQuotas and
+// memMaxes (and, if set, ioMax), each time inside a freshly created transient cgroup
+// v2 slice so the caps are actually enforced on the running process. Each resulting
+// row is prefixed with the limits that were applied.
+func runCgroupCases(cases []Config, cpuQuotas, memMaxes []string, ioMax string, iodevs []string) [][]string {
+	var ioDev string
+	if ioMax != "" {
+		dev, rate, err := parseIOMax(ioMax)
 		if err != nil {
 			log.Fatal(err)
 		}
-		eInitV := time.Since(t)
+		ioDev, ioMax = dev, rate
+	}
 
-		t = time.Now()
-		proof, err = prover.GenerateProof(id, challenge)
+	limitCases := cgroupCases(cpuQuotas, memMaxes, ioDev, ioMax)
+
+	var data [][]string
+	n := 0
+	total := len(limitCases) * len(cases)
+	for li, limits := range limitCases {
+		slice, err := newCgroupSlice(fmt.Sprintf("case-%d", li), limits)
 		if err != nil {
 			log.Fatal(err)
 		}
-		eExec := time.Since(t)
+		if err := slice.join(); err != nil {
+			log.Fatal(err)
+		}
 
-		t = time.Now()
-		err = validator.Validate(proof)
-		if err != nil {
+		for _, cfg := range cases {
+			log.Printf("test %v/%v starting (limits: %v)...", n+1, total, limits)
+			row := runCase(cfg, n, total, iodevs)
+			n++
+
+			ioCol := ""
+			if limits.ioDev != "" {
+				ioCol = fmt.Sprintf("/dev/%s:%s", limits.ioDev, limits.ioMax)
+			}
+			data = append(data, append([]string{limits.cpuQuota, limits.memMax, ioCol}, row...))
+		}
+
+		if err := slice.close(); err != nil {
 			log.Fatal(err)
 		}
-		eExecV := time.Since(t)
+	}
+
+	return data
+}
+
+// runFeatureCases runs every case in cases once per entry in featureSets. Each entry
+// names the CPU features to force off, joined with '+' to combine several in one pass
+// (e.g. "avx2+sse41"); an entry of "none" (or "") runs with the host's actual dispatch,
+// as the A/B baseline. Forcing a feature off only takes effect if the env var blake3
+// reads for it is set before that process (and its package-level dispatch init) starts
+// — see shared/cpufeat — so each case runs in a freshly exec'd child process rather
+// than in-process. Each resulting row is prefixed with the features that were forced
+// off, so it can be attributed to the dispatch path it measures (e.g. blake3 falling
+// back from AVX2 to its scalar implementation).
+func runFeatureCases(cases []Config, featureSets []string, iodevs []string) [][]string {
+	var data [][]string
+	n, total := 0, len(featureSets)*len(cases)
+
+	for _, spec := range featureSets {
+		label := spec
+		if label == "" {
+			label = "none"
+		}
 
-		err = init.Reset(id)
+		var disable []string
+		if label != "none" {
+			disable = strings.Split(label, "+")
+		}
+		env, err := cpufeat.EnvVars(disable)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		log.Printf("test %v/%v completed, %v", i+1, len(cases), time.Since(tStart))
+		for _, cfg := range cases {
+			log.Printf("test %v/%v starting (features off: %v)...", n+1, total, label)
+			row, err := runCaseInChildProcess(cfg, iodevs, env)
+			if err != nil {
+				log.Fatal(err)
+			}
+			n++
+			data = append(data, append([]string{label}, row...))
+		}
+	}
 
-		numFiles, _ := shared.NumFiles(cfg.SpacePerUnit, cfg.FileSize)
-		pfiles, pinfile := init.CalcParallelism()
-		pread := prover.CalcParallelism(numFiles)
+	return data
+}
 
-		data = append(data, []string{
-			strconv.Itoa(numFiles),
-			strconv.Itoa(pfiles),
-			strconv.Itoa(pinfile),
-			eInit.Round(time.Duration(time.Millisecond)).String(),
-			eInitV.Round(time.Duration(time.Microsecond)).String(),
-			strconv.Itoa(pread),
-			eExec.Round(time.Duration(time.Millisecond)).String(),
-			eExecV.Round(time.Duration(time.Microsecond)).String(),
-		})
+// runCaseInChildProcess runs a single case exactly like runCase, but re-execs this
+// binary as a child with env appended to its environment, so that env vars only read
+// once at process startup (such as blake3's dispatch overrides) actually take effect.
+// The child's stdout/stderr (its own progress bar and results table) are passed
+// through live so operators still get progress feedback; the row to fold into the
+// parent's report is collected separately via a throwaway -report CSV, to reuse the
+// exact row format runCase produces instead of re-deriving it from a parallel path.
+func runCaseInChildProcess(cfg Config, iodevs []string, env []string) ([]string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("resolve bench executable: %w", err)
 	}
 
-	header := []string{"NUMFILES", "P-FILES", "P-INFILE", "INIT", "INIT-V", "P-READ", "EXEC", "EXEC-V"}
-	metadata := getMetadata(defConfig, *disktype, *fstype, *desc)
+	report, err := os.CreateTemp("", "bench-feature-case-*.csv")
+	if err != nil {
+		return nil, fmt.Errorf("create temp report: %w", err)
+	}
+	report.Close()
+	defer os.Remove(report.Name())
+
+	args := []string{
+		"-mode", strconv.Itoa(int(single)),
+		"-datadir", cfg.DataDir,
+		"-space", strconv.FormatUint(cfg.SpacePerUnit, 10),
+		"-filesize", strconv.FormatUint(cfg.FileSize, 10),
+		"-pfiles", strconv.FormatUint(uint64(cfg.MaxWriteFilesParallelism), 10),
+		"-pinfile", strconv.FormatUint(uint64(cfg.MaxWriteInFileParallelism), 10),
+		"-pread", strconv.FormatUint(uint64(cfg.MaxReadFilesParallelism), 10),
+		"-iodev", strings.Join(iodevs, ","),
+		"-report", report.Name(),
+	}
 
-	exportTable(metadata, header, data, os.Stdout)
-	exportCSV(metadata, header, data, *report)
+	cmd := exec.Command(exe, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("run child case: %w", err)
+	}
 
-	if *memprof != "" {
-		f, err := os.Create(*memprof)
-		if err != nil {
-			log.Fatal("could not create memory profile: ", err)
+	return readReportRow(report.Name())
+}
+
+// readReportRow reads back a single-case -report CSV (metadata header, metadata
+// values, a blank separator, the data header, then one data row) and returns that row.
+func readReportRow(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open child report: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1 // metadata and data records have different widths
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse child report: %w", err)
+	}
+	const rowIdx = 3 // metadata header, metadata values, header, then data (the blank line is skipped)
+	if len(records) <= rowIdx {
+		return nil, fmt.Errorf("child report %s has no data row", path)
+	}
+	return records[rowIdx], nil
+}
+
+// runConcurrentCases runs, for each worker count in concurrencies, that many
+// Initializer/Prover pairs in parallel against distinct datadir subdirectories and
+// ids derived from cfg, to simulate several smeshers sharing a host. It reports
+// aggregate throughput and per-worker init/exec time distributions, surfacing where
+// the shared datadir device or CPU stops scaling linearly with added concurrency.
+func runConcurrentCases(cfg Config, concurrencies []string) [][]string {
+	var data [][]string
+
+	for _, cs := range concurrencies {
+		n, err := strconv.Atoi(strings.TrimSpace(cs))
+		if err != nil || n < 1 {
+			log.Fatalf("invalid -concurrency value %q", cs)
 		}
-		defer f.Close()
-		runtime.GC() // Get up-to-date statistics.
-		if err := pprof.WriteHeapProfile(f); err != nil {
-			log.Fatal("could not write memory profile: ", err)
+
+		log.Printf("concurrent test starting, concurrency=%v...", n)
+		wallStart := time.Now()
+
+		initTimes := make([]time.Duration, n)
+		execTimes := make([]time.Duration, n)
+
+		var wg sync.WaitGroup
+		wg.Add(n)
+		for w := 0; w < n; w++ {
+			go func(w int) {
+				defer wg.Done()
+
+				wcfg := cfg
+				wcfg.DataDir = filepath.Join(cfg.DataDir, fmt.Sprintf("worker-%d", w))
+				wid := append([]byte(nil), id...)
+				wid[0] ^= byte(w)
+
+				init := initialization.NewInitializer(&wcfg, shared.DisabledLogger{})
+				prover := proving.NewProver(&wcfg, shared.DisabledLogger{})
+
+				t := time.Now()
+				proof, err := init.Initialize(wid)
+				if err != nil {
+					log.Fatal(err)
+				}
+				initTimes[w] = time.Since(t)
+
+				t = time.Now()
+				if _, err = prover.GenerateProof(wid, challenge); err != nil {
+					log.Fatal(err)
+				}
+				execTimes[w] = time.Since(t)
+				_ = proof
+
+				if err := init.Reset(wid); err != nil {
+					log.Fatal(err)
+				}
+			}(w)
 		}
+		wg.Wait()
+
+		wall := time.Since(wallStart)
+		aggThroughput := float64(cfg.SpacePerUnit) * float64(n) / wall.Seconds()
+
+		initMean, initP50, initP95 := durationStats(initTimes)
+		execMean, execP50, execP95 := durationStats(execTimes)
+
+		data = append(data, []string{
+			strconv.Itoa(n),
+			bytefmt.ByteSize(uint64(aggThroughput)) + "/s",
+			initMean.Round(time.Millisecond).String(),
+			initP50.Round(time.Millisecond).String(),
+			initP95.Round(time.Millisecond).String(),
+			execMean.Round(time.Millisecond).String(),
+			execP50.Round(time.Millisecond).String(),
+			execP95.Round(time.Millisecond).String(),
+			wall.Round(time.Millisecond).String(),
+		})
+	}
+
+	return data
+}
+
+// durationStats returns the mean, p50 and p95 of d. d is not modified.
+func durationStats(d []time.Duration) (mean, p50, p95 time.Duration) {
+	sorted := append([]time.Duration(nil), d...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum time.Duration
+	for _, v := range sorted {
+		sum += v
 	}
+	mean = sum / time.Duration(len(sorted))
+	p50 = sorted[(50*(len(sorted)-1))/100]
+	p95 = sorted[(95*(len(sorted)-1))/100]
+	return mean, p50, p95
 }
 
 func exportCSV(metadata []kv, header []string, data [][]string, path string) {
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/bytefmt"
+)
+
+// cgroupLimits describes the resource caps applied to a single cgroup-mode case.
+type cgroupLimits struct {
+	cpuQuota string // e.g. "200%", empty means unconstrained
+	memMax   string // e.g. "2GiB", empty means unconstrained
+	ioDev    string // block device name, e.g. "nvme0n1", empty means unconstrained
+	ioMax    string // e.g. "200MiB", rbps/wbps rate applied to ioDev
+}
+
+func (l cgroupLimits) String() string {
+	parts := make([]string, 0, 3)
+	if l.cpuQuota != "" {
+		parts = append(parts, "cpu="+l.cpuQuota)
+	}
+	if l.memMax != "" {
+		parts = append(parts, "mem="+l.memMax)
+	}
+	if l.ioDev != "" {
+		parts = append(parts, fmt.Sprintf("io=%s:%s", l.ioDev, l.ioMax))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseCSVFlag splits a comma-separated flag value, e.g. "200%,400%,800%", dropping
+// surrounding whitespace and empty entries.
+func parseCSVFlag(v string) []string {
+	if v == "" {
+		return nil
+	}
+	var out []string
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// parseIOMax parses a "-io-max" value of the form "/dev/<name>:<rate>" into the
+// device name (without the /dev/ prefix) and the rate, validating that the rate
+// parses as a byte size.
+func parseIOMax(v string) (dev, rate string, err error) {
+	parts := strings.SplitN(v, ":", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid -io-max value %q, want /dev/<name>:<size>", v)
+	}
+	if _, err := bytefmt.ToBytes(parts[1]); err != nil {
+		return "", "", fmt.Errorf("invalid -io-max size %q: %w", v, err)
+	}
+	return strings.TrimPrefix(parts[0], "/dev/"), parts[1], nil
+}
+
+// cgroupCases expands the cartesian product of the requested cpu and memory limits
+// into the set of cgroupLimits to benchmark, each one also carrying the (single) io
+// device limit, if any. An empty cpuQuotas or memMaxes leaves that axis unconstrained.
+func cgroupCases(cpuQuotas, memMaxes []string, ioDev, ioMax string) []cgroupLimits {
+	if len(cpuQuotas) == 0 {
+		cpuQuotas = []string{""}
+	}
+	if len(memMaxes) == 0 {
+		memMaxes = []string{""}
+	}
+
+	cases := make([]cgroupLimits, 0, len(cpuQuotas)*len(memMaxes))
+	for _, cq := range cpuQuotas {
+		for _, mm := range memMaxes {
+			cases = append(cases, cgroupLimits{cpuQuota: cq, memMax: mm, ioDev: ioDev, ioMax: ioMax})
+		}
+	}
+	return cases
+}
@@ -0,0 +1,175 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"code.cloudfoundry.org/bytefmt"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupSlice is a transient cgroup v2 slice created for the duration of a single
+// cgroup-mode benchmark case.
+type cgroupSlice struct {
+	path string
+}
+
+// newCgroupSlice creates "<cgroupRoot>/postbench-<name>" and applies limits by
+// writing cpu.max, memory.max and io.max. It does not join the slice; call join to
+// move the current process into it.
+func newCgroupSlice(name string, limits cgroupLimits) (*cgroupSlice, error) {
+	// Best-effort: the cpu/memory/io controllers must be enabled on the parent
+	// before a child cgroup can set their limits. Ignore errors, since they're
+	// commonly already enabled (e.g. under systemd) and we'd rather surface a
+	// clearer error from the subsequent limit writes than from this one.
+	_ = os.WriteFile(filepath.Join(cgroupRoot, "cgroup.subtree_control"), []byte("+cpu +memory +io"), 0o644)
+
+	path := filepath.Join(cgroupRoot, "postbench-"+name)
+	if err := os.Mkdir(path, 0o755); err != nil && !os.IsExist(err) {
+		return nil, fmt.Errorf("create cgroup %s: %w", path, err)
+	}
+	s := &cgroupSlice{path: path}
+
+	// Always (re-)write cpu.max and memory.max, even when unconstrained, so a stale
+	// limit left behind by a prior run of the same case (e.g. after a crash skipped
+	// close) can't silently leak into this one.
+	cpuMax := "max 100000"
+	if limits.cpuQuota != "" {
+		quota, err := cpuMaxLine(limits.cpuQuota)
+		if err != nil {
+			return nil, err
+		}
+		cpuMax = quota
+	}
+	if err := s.write("cpu.max", cpuMax); err != nil {
+		return nil, err
+	}
+
+	memMax := "max"
+	if limits.memMax != "" {
+		max, err := bytefmt.ToBytes(limits.memMax)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -mem-max %q: %w", limits.memMax, err)
+		}
+		memMax = strconv.FormatUint(max, 10)
+	}
+	if err := s.write("memory.max", memMax); err != nil {
+		return nil, err
+	}
+
+	// Like cpu.max/memory.max above, clear any io.max entries already applied to
+	// this slice (e.g. left behind by a crashed prior run) before applying this
+	// run's limit, if any, so a stale throttle can't silently leak into this case.
+	if err := s.clearIOMax(); err != nil {
+		return nil, err
+	}
+	if limits.ioDev != "" {
+		line, err := ioMaxLine(limits.ioDev, limits.ioMax)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.write("io.max", line); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// clearIOMax resets every device currently listed in this slice's io.max back to
+// unlimited, by reading the device ids it currently applies to and rewriting each
+// as "<dev> max".
+func (s *cgroupSlice) clearIOMax() error {
+	data, err := os.ReadFile(filepath.Join(s.path, "io.max"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read io.max: %w", err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		dev := strings.Fields(line)[0]
+		if err := s.write("io.max", dev+" max"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// join moves the calling process into the slice's cgroup.procs.
+func (s *cgroupSlice) join() error {
+	return s.write("cgroup.procs", strconv.Itoa(os.Getpid()))
+}
+
+// close moves the process back to the root cgroup and removes the transient slice.
+func (s *cgroupSlice) close() error {
+	root := &cgroupSlice{path: cgroupRoot}
+	if err := root.join(); err != nil {
+		return fmt.Errorf("leave cgroup %s: %w", s.path, err)
+	}
+	return os.Remove(s.path)
+}
+
+func (s *cgroupSlice) write(file, value string) error {
+	p := filepath.Join(s.path, file)
+	if err := os.WriteFile(p, []byte(value), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", p, err)
+	}
+	return nil
+}
+
+// cpuMaxLine turns "200%" into the "<quota> <period>" line expected by cpu.max,
+// using the kernel's default 100ms period (200% == 2 full cores).
+func cpuMaxLine(pct string) (string, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(pct, "%"), 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid -cpu-quota %q: %w", pct, err)
+	}
+	const period = 100000 // microseconds
+	quota := int64(v / 100 * period)
+	return fmt.Sprintf("%d %d", quota, period), nil
+}
+
+// ioMaxLine resolves devName to its major:minor pair and formats the io.max line
+// applying rate as both the read and write byte-per-second cap.
+func ioMaxLine(devName, rate string) (string, error) {
+	major, minor, err := blockDeviceNumbers(devName)
+	if err != nil {
+		return "", err
+	}
+	bytesPerSec, err := bytefmt.ToBytes(rate)
+	if err != nil {
+		return "", fmt.Errorf("invalid -io-max rate %q: %w", rate, err)
+	}
+	return fmt.Sprintf("%d:%d rbps=%d wbps=%d", major, minor, bytesPerSec, bytesPerSec), nil
+}
+
+// blockDeviceNumbers resolves a block device name (e.g. "nvme0n1") to its
+// major:minor pair by reading /sys/class/block/<name>/dev.
+func blockDeviceNumbers(name string) (major, minor int, err error) {
+	data, err := os.ReadFile(filepath.Join("/sys/class/block", name, "dev"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("resolve block device %q: %w", name, err)
+	}
+	parts := strings.SplitN(strings.TrimSpace(string(data)), ":", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("unexpected dev format for %q: %q", name, data)
+	}
+	if major, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, fmt.Errorf("parse major for %q: %w", name, err)
+	}
+	if minor, err = strconv.Atoi(parts[1]); err != nil {
+		return 0, 0, fmt.Errorf("parse minor for %q: %w", name, err)
+	}
+	return major, minor, nil
+}
@@ -0,0 +1,30 @@
+//go:build linux
+
+package main
+
+import "testing"
+
+func TestCPUMaxLine(t *testing.T) {
+	cases := []struct {
+		pct  string
+		want string
+	}{
+		{"100%", "100000 100000"},
+		{"200%", "200000 100000"},
+		{"50%", "50000 100000"},
+	}
+
+	for _, c := range cases {
+		got, err := cpuMaxLine(c.pct)
+		if err != nil {
+			t.Fatalf("cpuMaxLine(%q) returned error: %v", c.pct, err)
+		}
+		if got != c.want {
+			t.Fatalf("cpuMaxLine(%q) = %q, want %q", c.pct, got, c.want)
+		}
+	}
+
+	if _, err := cpuMaxLine("not-a-number%"); err == nil {
+		t.Fatal("expected error for an unparseable quota")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package main
+
+import "fmt"
+
+// cgroupSlice is a no-op stand-in on platforms without cgroup v2 support.
+type cgroupSlice struct{}
+
+func newCgroupSlice(name string, limits cgroupLimits) (*cgroupSlice, error) {
+	return nil, fmt.Errorf("cgroup benchmark mode requires linux (cgroup v2)")
+}
+
+func (s *cgroupSlice) join() error { return nil }
+
+func (s *cgroupSlice) close() error { return nil }
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCSVFlag(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"single", "avx2", []string{"avx2"}},
+		{"multiple", "200%,400%,800%", []string{"200%", "400%", "800%"}},
+		{"whitespace and empty entries", " avx2 ,, sse41 ", []string{"avx2", "sse41"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := parseCSVFlag(c.in)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("parseCSVFlag(%q) = %v, want %v", c.in, got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseIOMax(t *testing.T) {
+	dev, rate, err := parseIOMax("/dev/nvme0n1:200MiB")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dev != "nvme0n1" || rate != "200MiB" {
+		t.Fatalf("parseIOMax() = (%q, %q), want (%q, %q)", dev, rate, "nvme0n1", "200MiB")
+	}
+
+	if _, _, err := parseIOMax("nvme0n1-200MiB"); err == nil {
+		t.Fatal("expected error for a value with no ':' separator")
+	}
+
+	if _, _, err := parseIOMax("/dev/nvme0n1:notasize"); err == nil {
+		t.Fatal("expected error for an unparseable size")
+	}
+}
+
+func TestCgroupCases(t *testing.T) {
+	got := cgroupCases([]string{"100%", "200%"}, []string{"2GiB"}, "nvme0n1", "200MiB")
+	want := []cgroupLimits{
+		{cpuQuota: "100%", memMax: "2GiB", ioDev: "nvme0n1", ioMax: "200MiB"},
+		{cpuQuota: "200%", memMax: "2GiB", ioDev: "nvme0n1", ioMax: "200MiB"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("cgroupCases() = %+v, want %+v", got, want)
+	}
+
+	// Leaving an axis empty should still produce a single unconstrained case on it.
+	got = cgroupCases(nil, nil, "", "")
+	want = []cgroupLimits{{}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("cgroupCases(nil, nil, ...) = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/process"
+)
+
+// ioSample is a point-in-time snapshot of disk I/O counters, summed across the
+// configured devices, used to compute the delta consumed by a single bench phase.
+type ioSample struct {
+	readBytes  uint64
+	writeBytes uint64
+	readOps    uint64
+	writeOps   uint64
+}
+
+// sampleIO reads the current disk I/O counters, restricted to devs if non-empty
+// (matched by device name, e.g. "nvme0n1"), and sums them into a single ioSample.
+func sampleIO(devs []string) (ioSample, error) {
+	counters, err := disk.IOCounters(devs...)
+	if err != nil {
+		return ioSample{}, fmt.Errorf("read disk io counters: %w", err)
+	}
+
+	var s ioSample
+	for _, c := range counters {
+		s.readBytes += c.ReadBytes
+		s.writeBytes += c.WriteBytes
+		s.readOps += c.ReadCount
+		s.writeOps += c.WriteCount
+	}
+	return s, nil
+}
+
+// sub returns the per-phase delta between a sample taken at the end of a phase (s)
+// and one taken at its start.
+func (s ioSample) sub(start ioSample) ioSample {
+	return ioSample{
+		readBytes:  s.readBytes - start.readBytes,
+		writeBytes: s.writeBytes - start.writeBytes,
+		readOps:    s.readOps - start.readOps,
+		writeOps:   s.writeOps - start.writeOps,
+	}
+}
+
+func (s ioSample) row() []string {
+	return []string{
+		strconv.FormatUint(s.readBytes, 10),
+		strconv.FormatUint(s.writeBytes, 10),
+		strconv.FormatUint(s.readOps, 10),
+		strconv.FormatUint(s.writeOps, 10),
+	}
+}
+
+const rssPollInterval = 100 * time.Millisecond
+
+// rssSampler polls the current process' resident set size every rssPollInterval and
+// tracks the maximum observed value, for attributing peak memory to a bench phase.
+type rssSampler struct {
+	proc   *process.Process
+	stopCh chan struct{}
+	doneCh chan uint64
+}
+
+// startRSSSampler begins polling the current process' RSS in the background. Call
+// stop to halt it and retrieve the peak RSS observed since it started.
+func startRSSSampler() (*rssSampler, error) {
+	proc, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		return nil, fmt.Errorf("open self process handle: %w", err)
+	}
+
+	s := &rssSampler{proc: proc, stopCh: make(chan struct{}), doneCh: make(chan uint64, 1)}
+	go s.run()
+	return s, nil
+}
+
+func (s *rssSampler) run() {
+	var peak uint64
+	if mi, err := s.proc.MemoryInfo(); err == nil {
+		peak = mi.RSS
+	}
+
+	ticker := time.NewTicker(rssPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if mi, err := s.proc.MemoryInfo(); err == nil && mi.RSS > peak {
+				peak = mi.RSS
+			}
+		case <-s.stopCh:
+			s.doneCh <- peak
+			return
+		}
+	}
+}
+
+// stop halts the sampler and returns the peak RSS observed during its lifetime.
+func (s *rssSampler) stop() uint64 {
+	close(s.stopCh)
+	return <-s.doneCh
+}
+
+// beginPhase starts disk-IO and RSS sampling for a single bench phase.
+func beginPhase(iodevs []string) (ioSample, *rssSampler) {
+	io, err := sampleIO(iodevs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rss, err := startRSSSampler()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return io, rss
+}
+
+// endPhase stops the sampling started by beginPhase and returns the phase's elapsed
+// time, disk-IO delta, and peak RSS.
+func endPhase(t time.Time, start ioSample, rss *rssSampler, iodevs []string) (time.Duration, ioSample, uint64) {
+	elapsed := time.Since(t)
+	peak := rss.stop()
+	end, err := sampleIO(iodevs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return elapsed, end.sub(start), peak
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIOSampleSub(t *testing.T) {
+	start := ioSample{readBytes: 100, writeBytes: 200, readOps: 5, writeOps: 10}
+	end := ioSample{readBytes: 150, writeBytes: 260, readOps: 8, writeOps: 12}
+
+	got := end.sub(start)
+	want := ioSample{readBytes: 50, writeBytes: 60, readOps: 3, writeOps: 2}
+	if got != want {
+		t.Fatalf("sub() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDurationStats(t *testing.T) {
+	in := []time.Duration{5 * time.Second, 1 * time.Second, 4 * time.Second, 2 * time.Second, 3 * time.Second}
+	orig := append([]time.Duration(nil), in...)
+
+	mean, p50, p95 := durationStats(in)
+	if mean != 3*time.Second {
+		t.Fatalf("mean = %v, want %v", mean, 3*time.Second)
+	}
+	if p50 != 3*time.Second {
+		t.Fatalf("p50 = %v, want %v", p50, 3*time.Second)
+	}
+	if p95 != 4*time.Second {
+		t.Fatalf("p95 = %v, want %v", p95, 4*time.Second)
+	}
+
+	if !reflect.DeepEqual(in, orig) {
+		t.Fatalf("durationStats mutated its input: got %v, want %v", in, orig)
+	}
+}
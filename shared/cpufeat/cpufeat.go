@@ -0,0 +1,52 @@
+// Package cpufeat maps CPU feature names to the environment variables that
+// github.com/zeebo/blake3 reads to decide which label-hashing implementation
+// (AVX2, SSE4.1, or pure Go) to dispatch to. That decision is made once, in
+// package-level var initializers that run before main(), so it can't be changed on
+// an already-running process — flipping klauspost/cpuid/v2 detection at runtime has
+// no effect on a choice blake3 already cached. The only way to force a feature off
+// is to set the corresponding env var before the process that will use blake3 first
+// starts, e.g. by re-executing as a child process per feature set under test.
+package cpufeat
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Env vars read once by github.com/zeebo/blake3's internal dispatch.
+const (
+	envDisableAVX2  = "BLAKE3_DISABLE_AVX2"
+	envDisableSSE41 = "BLAKE3_DISABLE_SSE41"
+	envPureGo       = "BLAKE3_PUREGO"
+)
+
+var envVars = map[string]string{
+	"avx2":   envDisableAVX2,
+	"sse41":  envDisableSSE41,
+	"purego": envPureGo,
+}
+
+// Names returns the recognised feature names, sorted, for building flag usage text.
+func Names() []string {
+	names := make([]string, 0, len(envVars))
+	for n := range envVars {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EnvVars returns the "KEY=1" entries to append to a child process' exec.Cmd.Env to
+// force each named feature off in it. It errors on an unrecognised name rather than
+// silently leaving that feature enabled.
+func EnvVars(disable []string) ([]string, error) {
+	vars := make([]string, 0, len(disable))
+	for _, f := range disable {
+		v, ok := envVars[f]
+		if !ok {
+			return nil, fmt.Errorf("unrecognised cpu feature %q (known: %v)", f, Names())
+		}
+		vars = append(vars, v+"=1")
+	}
+	return vars, nil
+}
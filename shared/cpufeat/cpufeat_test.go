@@ -0,0 +1,33 @@
+package cpufeat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNames(t *testing.T) {
+	got := Names()
+	want := []string{"avx2", "purego", "sse41"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Names() = %v, want %v", got, want)
+	}
+}
+
+func TestEnvVars(t *testing.T) {
+	got, err := EnvVars([]string{"avx2", "sse41"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"BLAKE3_DISABLE_AVX2=1", "BLAKE3_DISABLE_SSE41=1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("EnvVars() = %v, want %v", got, want)
+	}
+
+	if got, err := EnvVars(nil); err != nil || len(got) != 0 {
+		t.Fatalf("EnvVars(nil) = (%v, %v), want (empty, nil)", got, err)
+	}
+
+	if _, err := EnvVars([]string{"avx512"}); err == nil {
+		t.Fatal("expected error for an unrecognised feature name")
+	}
+}